@@ -0,0 +1,136 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(url string, maxRetries int, sp *spool) *client {
+	return &client{
+		apikey:   "test",
+		hostname: "test-host",
+		url:      url,
+		retry:    defaultRetryPolicy(maxRetries),
+		spool:    sp,
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "test",
+		},
+	}
+}
+
+func TestClientSendFlushesPayloadOnSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL, 0, nil)
+	c.payload.Write(line{Line: "hello"})
+	c.send(true)
+
+	if got := c.payload.Size(); got != 0 {
+		t.Fatalf("expected payload flushed after a successful send, got size %d", got)
+	}
+}
+
+// TestClientSendKeepsPayloadOnFailureWithoutSpool guards against the bug
+// where a failed send flushed the payload anyway: with no spool configured,
+// a batch that never gets a 2xx must stay buffered for the next attempt
+// instead of being silently discarded.
+func TestClientSendKeepsPayloadOnFailureWithoutSpool(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL, 0, nil)
+	c.payload.Write(line{Line: "hello"})
+	c.send(true)
+
+	if got := c.payload.Size(); got != 1 {
+		t.Fatalf("expected failed send to keep the line buffered, got size %d", got)
+	}
+}
+
+func TestClientSendSpoolsOnFailureThenFlushesPayload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	sp, err := newSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("newSpool: %v", err)
+	}
+
+	c := newTestClient(srv.URL, 0, sp)
+	c.payload.Write(line{Line: "hello"})
+	c.send(true)
+
+	if got := c.payload.Size(); got != 0 {
+		t.Fatalf("expected payload flushed once the batch was spooled, got size %d", got)
+	}
+
+	var drained []line
+	sp.Drain(time.Now().Add(time.Second), func(ls []line) bool {
+		drained = append(drained, ls...)
+		return true
+	})
+	if len(drained) != 1 {
+		t.Fatalf("expected the failed batch to be recoverable from the spool, got %d lines", len(drained))
+	}
+}
+
+func TestClientPostRetriesOn5xxThenSucceeds(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL, 5, nil)
+	c.retry.baseDelay = time.Millisecond
+	c.retry.maxDelay = 5 * time.Millisecond
+
+	if ok := c.post(sampleLines(1)); !ok {
+		t.Fatal("expected post to eventually succeed")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+}
+
+// TestClientCloseWaitsForInflightSend exercises the wg tracking added to
+// WriteLine/Close: a batch-filling WriteLine spawns an async send, and Close
+// must wait for it to finish rather than racing it.
+func TestClientCloseWaitsForInflightSend(t *testing.T) {
+	var handled int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&handled, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL, 0, nil)
+	c.monitor = &monitor{client: c, done: make(chan struct{}, 1)}
+
+	for i := 0; i < maxNumLines; i++ {
+		c.WriteLine(line{Line: "hello"})
+	}
+	c.Close()
+
+	if got := atomic.LoadInt32(&handled); got != 1 {
+		t.Fatalf("expected the in-flight send to complete before Close returned, handled=%d", got)
+	}
+}
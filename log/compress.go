@@ -0,0 +1,58 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionFromEnv returns the Content-Encoding to use for outgoing ingest
+// requests, driven by PP_LOG_COMPRESS=gzip|zstd|none. Defaults to no
+// compression when unset or unrecognised.
+func compressionFromEnv() string {
+	switch strings.ToLower(os.Getenv("PP_LOG_COMPRESS")) {
+	case "gzip":
+		return "gzip"
+	case "zstd":
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// encodeBody marshals lines as the ingest payload, optionally compressing it
+// per encoding ("gzip", "zstd", or "" for none). The JSON encoder writes
+// directly into the compressor so the marshaled payload is never fully
+// buffered uncompressed.
+func encodeBody(lines []line, encoding string) (io.Reader, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		if err := json.NewEncoder(&buf).Encode(&payload{Lines: lines}); err != nil {
+			return nil, err
+		}
+		return &buf, nil
+	}
+	if err := json.NewEncoder(w).Encode(&payload{Lines: lines}); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func decodePayload(t *testing.T, body []byte, encoding string) payload {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(body)
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("gzip.NewReader: %v", err)
+		}
+		defer gr.Close()
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatalf("zstd.NewReader: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	var got payload
+	if err := json.NewDecoder(r).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return got
+}
+
+func TestEncodeBodyRoundTrip(t *testing.T) {
+	lines := sampleLines(10)
+	for _, encoding := range []string{"", "gzip", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			r, err := encodeBody(lines, encoding)
+			if err != nil {
+				t.Fatalf("encodeBody: %v", err)
+			}
+			body, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			got := decodePayload(t, body, encoding)
+			if len(got.Lines) != len(lines) {
+				t.Fatalf("got %d lines, want %d", len(got.Lines), len(lines))
+			}
+			if got.Lines[3].Line != lines[3].Line {
+				t.Fatalf("got line %q, want %q", got.Lines[3].Line, lines[3].Line)
+			}
+		})
+	}
+}
+
+func TestCompressionFromEnv(t *testing.T) {
+	cases := map[string]string{
+		"":         "",
+		"none":     "",
+		"gzip":     "gzip",
+		"GZIP":     "gzip",
+		"zstd":     "zstd",
+		"bogus":    "",
+		"  gzip  ": "",
+	}
+	for in, want := range cases {
+		t.Setenv("PP_LOG_COMPRESS", in)
+		if got := compressionFromEnv(); got != want {
+			t.Errorf("compressionFromEnv(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// BenchmarkEncodeBody reports the encoded size and allocations for a
+// maxNumLines batch under each encoding, demonstrating the payload-size and
+// allocation reduction compression buys over sending raw JSON.
+func BenchmarkEncodeBody(b *testing.B) {
+	lines := sampleLines(maxNumLines)
+	for _, encoding := range []string{"", "gzip", "zstd"} {
+		encoding := encoding
+		name := encoding
+		if name == "" {
+			name = "none"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			var size int
+			for i := 0; i < b.N; i++ {
+				r, err := encodeBody(lines, encoding)
+				if err != nil {
+					b.Fatal(err)
+				}
+				body, err := ioutil.ReadAll(r)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(body)
+			}
+			b.ReportMetric(float64(size), "bytes/op")
+		})
+	}
+}
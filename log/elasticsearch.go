@@ -0,0 +1,158 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// esSink ships log lines to an Elasticsearch (or compatible) cluster using
+// the _bulk API with newline-delimited JSON.
+type esSink struct {
+	url      string
+	index    string
+	username string
+	password string
+	hostname string
+
+	payload *payload
+
+	// wg tracks flushes spawned by WriteLine so Close can wait for them to
+	// finish instead of racing an in-flight request.
+	wg sync.WaitGroup
+}
+
+func newElasticsearchSink() Sink {
+	baseurl := os.Getenv("PP_LOG_ES_URL")
+	if baseurl == "" {
+		return nil
+	}
+	index := os.Getenv("PP_LOG_ES_INDEX")
+	if index == "" {
+		index = "logs"
+	}
+	hostname := os.Getenv("PP_HOSTNAME")
+	if hostname == "" {
+		hostname = "hostname.not.provided"
+	}
+	return &esSink{
+		url:      strings.TrimRight(baseurl, "/") + "/_bulk",
+		index:    index,
+		username: os.Getenv("PP_LOG_ES_USERNAME"),
+		password: os.Getenv("PP_LOG_ES_PASSWORD"),
+		hostname: hostname,
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "elasticsearch",
+		},
+	}
+}
+
+func init() {
+	RegisterSink("elasticsearch", newElasticsearchSink)
+}
+
+// WriteLine buffers l and, once the batch is full, spawns an async flush so
+// the line actually ships instead of sitting in memory until Close.
+func (s *esSink) WriteLine(l line) bool {
+	ready := s.payload.Write(l)
+	if ready {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.Flush()
+		}()
+	}
+	return ready
+}
+
+// waitInflight blocks until any flushes spawned by WriteLine have completed,
+// or until deadline passes, whichever comes first.
+func (s *esSink) waitInflight(deadline time.Time) {
+	blockUntilDone(&s.wg, deadline)
+}
+
+// indexName supports daily rotating indices (logs-2020.01.02) the way most
+// ES deployments expect: the current date is appended to the configured
+// index. An index containing "%" is instead used as a Go time layout and
+// formatted with the current time. A fixed, unsuffixed index name is not
+// supported.
+func (s *esSink) indexName() string {
+	if strings.Contains(s.index, "%") {
+		return time.Now().Format(s.index)
+	}
+	return fmt.Sprintf("%s-%s", s.index, time.Now().Format("2006.01.02"))
+}
+
+func (s *esSink) Flush() error {
+	if s.payload.Size() == 0 {
+		return nil
+	}
+	s.payload.mu.Lock()
+	lines := make([]line, len(s.payload.Lines))
+	copy(lines, s.payload.Lines)
+	s.payload.mu.Unlock()
+	defer s.payload.Flush()
+
+	var buf bytes.Buffer
+	index := s.indexName()
+	for _, l := range lines {
+		meta := map[string]interface{}{"index": map[string]string{"_index": index}}
+		metaLine, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		buf.Write(metaLine)
+		buf.WriteByte('\n')
+		doc, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		buf.Write(doc)
+		buf.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	start := time.Now()
+	defer func() {
+		batchSendDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Println("error posting to elasticsearch", err)
+		batchesSentTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		err := fmt.Errorf("elasticsearch bulk request failed: %s", string(b))
+		fmt.Println(err)
+		batchesSentTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	ioutil.ReadAll(resp.Body)
+	batchesSentTotal.WithLabelValues("success").Inc()
+	return nil
+}
+
+func (s *esSink) Close() error {
+	s.waitInflight(time.Now().Add(defaultShutdownDeadline))
+	return s.Flush()
+}
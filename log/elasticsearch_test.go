@@ -0,0 +1,81 @@
+package log
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestESSink(url string) *esSink {
+	return &esSink{
+		url:      url,
+		index:    "logs",
+		hostname: "test-host",
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "elasticsearch",
+		},
+	}
+}
+
+// TestESSinkWriteLineFlushesOnBatchFull guards against the bug where
+// WriteLine's ready-to-flush signal was discarded, leaving esSink batches
+// sitting in memory until Close.
+func TestESSinkWriteLineFlushesOnBatchFull(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestESSink(srv.URL)
+	for i := 0; i < maxNumLines; i++ {
+		s.WriteLine(line{Line: "hello", Timestamp: int64(i)})
+	}
+	s.waitInflight(time.Now().Add(time.Second))
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected filling a batch to self-trigger one flush, got %d requests", got)
+	}
+}
+
+func TestESSinkFlushSendsBulkNDJSON(t *testing.T) {
+	var lineCount int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "application/x-ndjson" {
+			t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lineCount++
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestESSink(srv.URL)
+	s.payload.Write(line{Line: "hello"})
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	// each log line produces two ndjson lines: the bulk action/meta line and
+	// the document itself.
+	if lineCount != 2 {
+		t.Fatalf("got %d ndjson lines, want 2", lineCount)
+	}
+}
+
+func TestESSinkIndexNameRotatesDaily(t *testing.T) {
+	s := newTestESSink("http://example.invalid")
+	name := s.indexName()
+	if len(name) <= len("logs-") {
+		t.Fatalf("expected a date-suffixed index name, got %q", name)
+	}
+}
@@ -0,0 +1,74 @@
+package log
+
+import "time"
+
+type fieldType int
+
+const (
+	stringType fieldType = iota
+	intType
+	durationType
+	errorType
+	anyType
+)
+
+// Field is a typed key/value pair attached to a log entry, modeled on
+// zap.Field so call sites avoid the interface{} boxing and string-matching
+// that Log(keyvals ...interface{}) relies on.
+type Field struct {
+	Key string
+	typ fieldType
+	str string
+	num int64
+	dur time.Duration
+	err error
+	any interface{}
+}
+
+// String creates a string-valued Field.
+func String(key, val string) Field {
+	return Field{Key: key, typ: stringType, str: val}
+}
+
+// Int creates an int-valued Field.
+func Int(key string, val int) Field {
+	return Field{Key: key, typ: intType, num: int64(val)}
+}
+
+// Error creates a Field under the key "error" from err. A nil err still
+// produces a field so callers can log it unconditionally.
+func Error(err error) Field {
+	return Field{Key: "error", typ: errorType, err: err}
+}
+
+// Duration creates a Field whose value is val formatted as a Go duration
+// string (e.g. "1.5s").
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, typ: durationType, dur: val}
+}
+
+// Any creates a Field from an arbitrary value for cases the typed
+// constructors don't cover.
+func Any(key string, val interface{}) Field {
+	return Field{Key: key, typ: anyType, any: val}
+}
+
+// value returns the field's value in the form the keyval-based Logger
+// pipeline expects.
+func (f Field) value() interface{} {
+	switch f.typ {
+	case stringType:
+		return f.str
+	case intType:
+		return f.num
+	case durationType:
+		return f.dur.String()
+	case errorType:
+		if f.err == nil {
+			return nil
+		}
+		return f.err.Error()
+	default:
+		return f.any
+	}
+}
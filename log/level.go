@@ -0,0 +1,66 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// Level is a logging severity, ordered so comparisons (lvl >= minLevel)
+// work the way callers expect.
+type Level int32
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+func parseLevel(s string) Level {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return DebugLevel
+	case "warn", "warning":
+		return WarnLevel
+	case "error":
+		return ErrorLevel
+	default:
+		return InfoLevel
+	}
+}
+
+var (
+	minLevelOnce sync.Once
+	minLevelVal  Level
+)
+
+// minLevel is the minimum level that will be logged, driven by PP_LOG_LEVEL.
+// It defaults to InfoLevel when unset or unrecognised.
+func minLevel() Level {
+	minLevelOnce.Do(func() {
+		minLevelVal = parseLevel(os.Getenv("PP_LOG_LEVEL"))
+	})
+	return minLevelVal
+}
+
+// levelAllowed reports whether lvl passes the level filter alone, with no
+// sampling applied. Used by the Log(keyvals...) shim, since sampling lives
+// in StructuredLogger — a message logged through StructuredLogger and
+// forwarded to the shim shouldn't consume a second sampling slot.
+func levelAllowed(lvl Level) bool {
+	return lvl >= minLevel()
+}
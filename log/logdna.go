@@ -2,7 +2,6 @@ package log
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -18,9 +17,19 @@ import (
 const logdnaBaseURL = "https://logs.logdna.com/logs/ingest"
 const maxNumLines = 100
 
+// maxQueueLines bounds how many lines a payload will buffer in memory; once
+// hit, the oldest lines are dropped so a stalled sink can't grow without
+// limit. This is the "backpressure" half of resilient ingest — the disk
+// spool on the logdna client is what actually protects against data loss.
+const maxQueueLines = 1000
+
 type payload struct {
 	Lines []line `json:"lines"`
 	mu    *sync.RWMutex
+
+	// name identifies which sink this payload belongs to, so its queue
+	// depth is reported separately from every other sink's.
+	name string
 }
 
 // Flush payload
@@ -28,13 +37,21 @@ func (p *payload) Flush() {
 	p.mu.Lock()
 	p.Lines = []line{}
 	p.mu.Unlock()
+	queueDepth.WithLabelValues(p.name).Set(0)
 }
 
 func (p *payload) Write(l line) bool {
 	p.mu.Lock()
+	if len(p.Lines) >= maxQueueLines {
+		p.Lines = p.Lines[1:]
+		fmt.Println("log queue full, dropping oldest line")
+		linesDroppedTotal.WithLabelValues("queue_full").Inc()
+	}
 	p.Lines = append(p.Lines, l)
 	readytosend := len(p.Lines) >= maxNumLines
+	queueDepth.WithLabelValues(p.name).Set(float64(len(p.Lines)))
 	p.mu.Unlock()
+	linesEnqueuedTotal.Inc()
 	return readytosend
 }
 
@@ -63,11 +80,38 @@ type client struct {
 	url      string
 
 	monitor *monitor
+	retry   retryPolicy
+	spool   *spool
 
 	mu      sync.Mutex
 	payload *payload
+
+	// wg tracks sends spawned by WriteLine so shutdown can wait for them
+	// to finish instead of racing an in-flight POST.
+	wg sync.WaitGroup
+}
+
+// waitInflight blocks until any sends spawned by WriteLine have completed,
+// or until deadline passes, whichever comes first.
+func (c *client) waitInflight(deadline time.Time) {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+	}
 }
 
+// send snapshots the buffered payload and attempts to post it, retrying
+// transient failures per c.retry. Lines only leave the in-memory payload
+// once they've either been accepted (2xx) or handed off successfully to the
+// on-disk spool — if neither happens (no spool configured, or the spool
+// write itself fails) the payload is left untouched so the next send (the
+// monitor tick, or the next WriteLine that fills a batch) retries them
+// instead of silently dropping the batch.
 func (c *client) send(force bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -75,14 +119,51 @@ func (c *client) send(force bool) {
 		return
 	}
 	c.payload.mu.Lock()
-	defer func() {
+	lines := make([]line, len(c.payload.Lines))
+	copy(lines, c.payload.Lines)
+	c.payload.mu.Unlock()
+
+	// flushSent drops only the snapshotted lines, keeping any that WriteLine
+	// appended while the post was in flight.
+	flushSent := func() {
+		c.payload.mu.Lock()
+		n := len(lines)
+		if n > len(c.payload.Lines) {
+			n = len(c.payload.Lines)
+		}
+		c.payload.Lines = c.payload.Lines[n:]
+		remaining := len(c.payload.Lines)
 		c.payload.mu.Unlock()
-		c.payload.Flush()
-	}()
-	body, err := json.Marshal(c.payload)
+		queueDepth.WithLabelValues(c.payload.name).Set(float64(remaining))
+	}
+
+	if c.post(lines) {
+		flushSent()
+		return
+	}
+	if c.spool == nil {
+		return
+	}
+	if err := c.spool.Write(lines); err != nil {
+		fmt.Println("error spooling log payload", err)
+		return
+	}
+	flushSent()
+}
+
+// post attempts to deliver lines to LogDNA, retrying 5xx/429/network errors
+// with exponential backoff and jitter. Returns true once a 2xx is received.
+func (c *client) post(lines []line) bool {
+	encoding := compressionFromEnv()
+	encoded, err := encodeBody(lines, encoding)
 	if err != nil {
 		fmt.Println("Error marshaling logdna payload", err)
-		return
+		return false
+	}
+	body, err := ioutil.ReadAll(encoded)
+	if err != nil {
+		fmt.Println("Error marshaling logdna payload", err)
+		return false
 	}
 	apiurl, _ := url.Parse(c.url)
 	apiurl.User = url.User(c.apikey)
@@ -93,59 +174,95 @@ func (c *client) send(force bool) {
 	qs.Set("now", strconv.FormatInt(time.Now().UnixNano()/1000000, 10))
 	qs.Set("tags", strings.Join(c.tags, ","))
 	apiurl.RawQuery = qs.Encode()
+	reqURL := apiurl.String()
 
-	resp, err := http.Post(apiurl.String(), "application/json", bytes.NewReader(body))
-	if err != nil {
-		fmt.Println("error constructing logdna url", err)
-		return
-	}
-	defer resp.Body.Close()
-	// read error once get unexpected HTTP status code
-	if resp.StatusCode >= 400 {
-		b, err := ioutil.ReadAll(resp.Body)
+	start := time.Now()
+	defer func() {
+		batchSendDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewReader(body))
+		if err != nil {
+			fmt.Println("error building logdna request", err)
+			batchesSentTotal.WithLabelValues("error").Inc()
+			return false
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			batchesSentTotal.WithLabelValues("success").Inc()
+			return true
+		}
 		if err != nil {
-			fmt.Println("error reading logdna response body", err)
+			fmt.Println("error posting logdna payload", err)
 		} else {
-			fmt.Println("error making logdna injest request", string(b))
+			b, readErr := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if readErr != nil {
+				fmt.Println("error reading logdna response body", readErr)
+			} else {
+				fmt.Println("error making logdna ingest request", string(b))
+			}
+		}
+		if attempt >= c.retry.maxRetries || !c.retry.shouldRetry(resp, err) {
+			batchesSentTotal.WithLabelValues("error").Inc()
+			return false
 		}
-	} else {
-		ioutil.ReadAll(resp.Body)
+		time.Sleep(c.retry.delay(attempt, resp))
 	}
 }
 
 type dnalog struct {
-	next   Logger
-	client *client
+	next     Logger
+	sink     Sink
+	hostname string
 }
 
-func (l *dnalog) Log(keyvals ...interface{}) error {
-	if l.client != nil {
-		var msg string
-		lvl := "info"
-		kv := make(map[string]interface{})
-		for i, val := range keyvals {
-			valstr := fmt.Sprintf("%v", val)
-			switch valstr {
-			case "msg":
-				msg = keyvals[i+1].(string)
-				break
-			case "level":
-				lvl = fmt.Sprintf("%v", keyvals[i+1])
-				break
-			default:
-				if i%2 == 0 {
-					kv[valstr] = keyvals[i+1]
-				}
+// parseKeyvals pulls "msg" and "level" out of a go-kit style keyval list,
+// treating everything else as metadata. Unlike a naive string-match switch,
+// it tolerates non-string keys and an odd-length list instead of panicking.
+func parseKeyvals(keyvals []interface{}) (msg, lvl string, kv map[string]interface{}) {
+	lvl = "info"
+	kv = make(map[string]interface{})
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			continue
+		}
+		val := keyvals[i+1]
+		switch key {
+		case "msg":
+			if s, ok := val.(string); ok {
+				msg = s
+			} else {
+				msg = fmt.Sprintf("%v", val)
 			}
+		case "level":
+			lvl = fmt.Sprintf("%v", val)
+		default:
+			kv[key] = val
 		}
-		if readytosend := l.client.payload.Write(line{
-			Timestamp: time.Now().UnixNano() / 1000000,
-			App:       l.client.hostname,
-			Line:      msg,
-			Level:     lvl,
-			Meta:      kv,
-		}); readytosend {
-			go l.client.send(false)
+	}
+	return
+}
+
+func (l *dnalog) Log(keyvals ...interface{}) error {
+	if l.sink != nil {
+		msg, lvl, kv := parseKeyvals(keyvals)
+		if levelAllowed(parseLevel(lvl)) {
+			l.sink.WriteLine(line{
+				Timestamp: time.Now().UnixNano() / 1000000,
+				App:       l.hostname,
+				Line:      msg,
+				Level:     lvl,
+				Meta:      kv,
+			})
 		}
 	}
 	if l.next != nil {
@@ -155,10 +272,9 @@ func (l *dnalog) Log(keyvals ...interface{}) error {
 }
 
 func (l *dnalog) Close() error {
-	if l.client != nil {
-		l.client.send(true)
-		l.client.monitor.done <- struct{}{}
-		l.client = nil
+	if l.sink != nil {
+		l.sink.Close()
+		l.sink = nil
 	}
 	return nil
 }
@@ -185,6 +301,44 @@ func getAddr() (string, string) {
 	return "", ""
 }
 
+// WriteLine implements Sink by buffering the line and signalling once the
+// batch is large enough to flush.
+func (c *client) WriteLine(l line) bool {
+	ready := c.payload.Write(l)
+	if ready {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.send(false)
+		}()
+	}
+	return ready
+}
+
+// Flush implements Sink by forcing an immediate send of whatever is buffered.
+func (c *client) Flush() error {
+	c.send(true)
+	return nil
+}
+
+// closeSpoolDeadline bounds how long Close will block draining the on-disk
+// spool before giving up and leaving the rest for the next process.
+const closeSpoolDeadline = 5 * time.Second
+
+// Close implements Sink by flushing, draining any spooled backlog within a
+// bounded deadline, and stopping the background monitor.
+func (c *client) Close() error {
+	c.send(true)
+	c.waitInflight(time.Now().Add(closeSpoolDeadline))
+	if c.spool != nil {
+		c.spool.Drain(time.Now().Add(closeSpoolDeadline), c.post)
+	}
+	if c.monitor != nil {
+		c.monitor.done <- struct{}{}
+	}
+	return nil
+}
+
 type monitor struct {
 	client *client
 	done   chan struct{}
@@ -205,52 +359,131 @@ func (m *monitor) run() {
 var dnaGlobalLock = sync.Mutex{}
 var globalClient *client
 
-// newDNALogger returns a log dna logger
-func newDNALogger(next Logger) LoggerCloser {
-	var c *client
+// newLogdnaClient builds a client from the PP_LOG_* environment variables,
+// wiring up its background flush monitor. Returns nil if PP_LOG_KEY is unset.
+func newLogdnaClient() *client {
 	apikey := os.Getenv("PP_LOG_KEY")
-	if apikey != "" {
-		dnaGlobalLock.Lock()
-		defer dnaGlobalLock.Unlock()
-		if globalClient == nil {
-			hostname := os.Getenv("PP_HOSTNAME")
-			if hostname == "" {
-				hostname = "hostname.not.provided"
-			}
-			tags := []string{}
-			tagstr := os.Getenv("PP_LOG_TAGS")
-			if tagstr != "" {
-				tags = strings.Split(tagstr, ",")
-			}
-			logurl := logdnaBaseURL
-			logurlstr := os.Getenv("PP_LOG_URL")
-			if logurlstr != "" {
-				logurl = logurlstr
-			}
-			ip, mac := getAddr()
-			globalClient = &client{
-				apikey:   apikey,
-				hostname: hostname,
-				mac:      mac,
-				ip:       ip,
-				tags:     tags,
-				url:      logurl,
-				payload: &payload{
-					Lines: make([]line, 0),
-					mu:    &sync.RWMutex{},
-				},
-			}
-			m := &monitor{
-				client: globalClient,
-				done:   make(chan struct{}, 1),
+	if apikey == "" {
+		return nil
+	}
+	hostname := os.Getenv("PP_HOSTNAME")
+	if hostname == "" {
+		hostname = "hostname.not.provided"
+	}
+	tags := []string{}
+	tagstr := os.Getenv("PP_LOG_TAGS")
+	if tagstr != "" {
+		tags = strings.Split(tagstr, ",")
+	}
+	logurl := logdnaBaseURL
+	logurlstr := os.Getenv("PP_LOG_URL")
+	if logurlstr != "" {
+		logurl = logurlstr
+	}
+	ip, mac := getAddr()
+
+	maxRetries := 5
+	if v := os.Getenv("PP_LOG_MAX_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			maxRetries = n
+		}
+	}
+
+	var sp *spool
+	if dir := os.Getenv("PP_LOG_SPOOL_DIR"); dir != "" {
+		var maxBytes int64
+		if v := os.Getenv("PP_LOG_SPOOL_MAX_BYTES"); v != "" {
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				maxBytes = n
 			}
-			globalClient.monitor = m
-			go m.run()
-			c = globalClient
+		}
+		s, err := newSpool(dir, maxBytes)
+		if err != nil {
+			fmt.Println("error opening log spool", err)
+		} else {
+			sp = s
 		}
 	}
-	return &dnalog{
-		next:   next,
+
+	c := &client{
+		apikey:   apikey,
+		hostname: hostname,
+		mac:      mac,
+		ip:       ip,
+		tags:     tags,
+		url:      logurl,
+		retry:    defaultRetryPolicy(maxRetries),
+		spool:    sp,
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "logdna",
+		},
+	}
+	m := &monitor{
 		client: c,
+		done:   make(chan struct{}, 1),
+	}
+	c.monitor = m
+	go m.run()
+	if sp != nil {
+		// best-effort: replay anything left over from a previous crash
+		// without blocking startup.
+		go sp.Drain(time.Now().Add(closeSpoolDeadline), c.post)
+	}
+	return c
+}
+
+func init() {
+	RegisterSink("logdna", func() Sink {
+		c := newLogdnaClient()
+		if c == nil {
+			return nil
+		}
+		return c
+	})
+}
+
+var (
+	defaultSinkOnce sync.Once
+	defaultSinkVal  Sink
+)
+
+// defaultSink resolves the Sink every dnalog instance writes to: the
+// fan-out built from PP_LOG_SINK when it's set, falling back to the
+// singleton LogDNA client driven by PP_LOG_KEY. Resolved once per process
+// so repeated newDNALogger calls (one per logger instance) share a single
+// sink and, in the LogDNA fallback case, a single background monitor. The
+// signal handler is installed here too, whichever branch resolves a sink,
+// so graceful shutdown covers PP_LOG_SINK the same as the LogDNA fallback.
+func defaultSink() Sink {
+	defaultSinkOnce.Do(func() {
+		if s := sinksFromEnv(); s != nil {
+			defaultSinkVal = s
+		} else if os.Getenv("PP_LOG_KEY") != "" {
+			dnaGlobalLock.Lock()
+			globalClient = newLogdnaClient()
+			dnaGlobalLock.Unlock()
+			defaultSinkVal = globalClient
+		}
+		if defaultSinkVal != nil {
+			installSignalHandlerOnce.Do(func() {
+				InstallSignalHandler()
+			})
+		}
+	})
+	return defaultSinkVal
+}
+
+// newDNALogger returns a log dna logger
+func newDNALogger(next Logger) LoggerCloser {
+	hostname := os.Getenv("PP_HOSTNAME")
+	if hostname == "" {
+		hostname = "hostname.not.provided"
+	}
+	return &dnalog{
+		next:     next,
+		sink:     defaultSink(),
+		hostname: hostname,
 	}
 }
@@ -0,0 +1,153 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lokiStream is a single labelled stream of values as expected by Loki's
+// push API: https://grafana.com/docs/loki/latest/api/#push-log-entries-to-loki
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+// lokiSink ships log lines to a Grafana Loki push endpoint, tagging every
+// entry with a stream built from the configured tags and hostname.
+type lokiSink struct {
+	url      string
+	hostname string
+	tags     []string
+
+	payload *payload
+
+	// wg tracks flushes spawned by WriteLine so Close can wait for them to
+	// finish instead of racing an in-flight POST.
+	wg sync.WaitGroup
+}
+
+func newLokiSink() Sink {
+	baseurl := os.Getenv("PP_LOG_LOKI_URL")
+	if baseurl == "" {
+		return nil
+	}
+	hostname := os.Getenv("PP_HOSTNAME")
+	if hostname == "" {
+		hostname = "hostname.not.provided"
+	}
+	var tags []string
+	if tagstr := os.Getenv("PP_LOG_TAGS"); tagstr != "" {
+		tags = strings.Split(tagstr, ",")
+	}
+	return &lokiSink{
+		url:      strings.TrimRight(baseurl, "/") + "/loki/api/v1/push",
+		hostname: hostname,
+		tags:     tags,
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "loki",
+		},
+	}
+}
+
+func init() {
+	RegisterSink("loki", newLokiSink)
+}
+
+// WriteLine buffers l and, once the batch is full, spawns an async flush so
+// the line actually ships instead of sitting in memory until Close.
+func (s *lokiSink) WriteLine(l line) bool {
+	ready := s.payload.Write(l)
+	if ready {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.Flush()
+		}()
+	}
+	return ready
+}
+
+// waitInflight blocks until any flushes spawned by WriteLine have completed,
+// or until deadline passes, whichever comes first.
+func (s *lokiSink) waitInflight(deadline time.Time) {
+	blockUntilDone(&s.wg, deadline)
+}
+
+func (s *lokiSink) labels() map[string]string {
+	labels := map[string]string{"hostname": s.hostname}
+	for _, t := range s.tags {
+		if t == "" {
+			continue
+		}
+		labels[t] = "true"
+	}
+	return labels
+}
+
+func (s *lokiSink) Flush() error {
+	if s.payload.Size() == 0 {
+		return nil
+	}
+	s.payload.mu.Lock()
+	lines := make([]line, len(s.payload.Lines))
+	copy(lines, s.payload.Lines)
+	s.payload.mu.Unlock()
+	defer s.payload.Flush()
+
+	labels := s.labels()
+	values := make([][2]string, 0, len(lines))
+	for _, l := range lines {
+		values = append(values, [2]string{
+			strconv.FormatInt(l.Timestamp*int64(time.Millisecond), 10),
+			l.Line,
+		})
+	}
+	push := lokiPush{Streams: []lokiStream{{Stream: labels, Values: values}}}
+	body, err := json.Marshal(push)
+	if err != nil {
+		fmt.Println("error marshaling loki payload", err)
+		return err
+	}
+
+	start := time.Now()
+	defer func() {
+		batchSendDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	resp, err := http.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Println("error posting to loki", err)
+		batchesSentTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		err := fmt.Errorf("loki ingest request failed: %s", string(b))
+		fmt.Println(err)
+		batchesSentTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	ioutil.ReadAll(resp.Body)
+	batchesSentTotal.WithLabelValues("success").Inc()
+	return nil
+}
+
+func (s *lokiSink) Close() error {
+	s.waitInflight(time.Now().Add(defaultShutdownDeadline))
+	return s.Flush()
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestLokiSink(url string) *lokiSink {
+	return &lokiSink{
+		url:      url,
+		hostname: "test-host",
+		tags:     []string{"env:test"},
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "loki",
+		},
+	}
+}
+
+// TestLokiSinkWriteLineFlushesOnBatchFull guards against the bug where
+// WriteLine's ready-to-flush signal was discarded: filling a batch must
+// actually ship it without a caller ever invoking Flush or Close.
+func TestLokiSinkWriteLineFlushesOnBatchFull(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestLokiSink(srv.URL)
+	for i := 0; i < maxNumLines; i++ {
+		s.WriteLine(line{Line: "hello", Timestamp: int64(i)})
+	}
+	s.waitInflight(time.Now().Add(time.Second))
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected filling a batch to self-trigger one flush, got %d requests", got)
+	}
+	if got := s.payload.Size(); got != 0 {
+		t.Fatalf("expected payload flushed after the self-triggered send, got size %d", got)
+	}
+}
+
+func TestLokiSinkFlushSendsExpectedPayload(t *testing.T) {
+	var got lokiPush
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestLokiSink(srv.URL)
+	s.payload.Write(line{Line: "hello", Timestamp: 1000})
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if len(got.Streams) != 1 || len(got.Streams[0].Values) != 1 {
+		t.Fatalf("unexpected loki push shape: %+v", got)
+	}
+	if got.Streams[0].Stream["hostname"] != "test-host" || got.Streams[0].Stream["env:test"] != "true" {
+		t.Fatalf("unexpected stream labels: %+v", got.Streams[0].Stream)
+	}
+	if got.Streams[0].Values[0][1] != "hello" {
+		t.Fatalf("unexpected line value: %+v", got.Streams[0].Values[0])
+	}
+}
@@ -0,0 +1,187 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLP/HTTP logs JSON shapes, trimmed down to the fields this package emits.
+// See https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+type otlpLogRecord struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	SeverityText string         `json:"severityText"`
+	Body         otlpAnyValue   `json:"body"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpScopeLogs struct {
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+// otlpSink ships log lines as OTLP/HTTP logs (JSON encoding) to a collector.
+type otlpSink struct {
+	url      string
+	hostname string
+	headers  map[string]string
+
+	payload *payload
+
+	// wg tracks flushes spawned by WriteLine so Close can wait for them to
+	// finish instead of racing an in-flight request.
+	wg sync.WaitGroup
+}
+
+func newOTLPSink() Sink {
+	baseurl := os.Getenv("PP_LOG_OTLP_URL")
+	if baseurl == "" {
+		return nil
+	}
+	hostname := os.Getenv("PP_HOSTNAME")
+	if hostname == "" {
+		hostname = "hostname.not.provided"
+	}
+	headers := map[string]string{}
+	if hdrstr := os.Getenv("PP_LOG_OTLP_HEADERS"); hdrstr != "" {
+		for _, kv := range strings.Split(hdrstr, ",") {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) == 2 {
+				headers[parts[0]] = parts[1]
+			}
+		}
+	}
+	url := baseurl
+	if !strings.HasSuffix(url, "/v1/logs") {
+		url = strings.TrimRight(url, "/") + "/v1/logs"
+	}
+	return &otlpSink{
+		url:      url,
+		hostname: hostname,
+		headers:  headers,
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "otlp",
+		},
+	}
+}
+
+func init() {
+	RegisterSink("otlp", newOTLPSink)
+}
+
+// WriteLine buffers l and, once the batch is full, spawns an async flush so
+// the line actually ships instead of sitting in memory until Close.
+func (s *otlpSink) WriteLine(l line) bool {
+	ready := s.payload.Write(l)
+	if ready {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.Flush()
+		}()
+	}
+	return ready
+}
+
+// waitInflight blocks until any flushes spawned by WriteLine have completed,
+// or until deadline passes, whichever comes first.
+func (s *otlpSink) waitInflight(deadline time.Time) {
+	blockUntilDone(&s.wg, deadline)
+}
+
+func (s *otlpSink) Flush() error {
+	if s.payload.Size() == 0 {
+		return nil
+	}
+	s.payload.mu.Lock()
+	lines := make([]line, len(s.payload.Lines))
+	copy(lines, s.payload.Lines)
+	s.payload.mu.Unlock()
+	defer s.payload.Flush()
+
+	records := make([]otlpLogRecord, 0, len(lines))
+	for _, l := range lines {
+		attrs := []otlpKeyValue{{Key: "app", Value: otlpAnyValue{StringValue: l.App}}}
+		for k, v := range l.Meta {
+			attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: fmt.Sprintf("%v", v)}})
+		}
+		records = append(records, otlpLogRecord{
+			TimeUnixNano: strconv.FormatInt(l.Timestamp*1e6, 10),
+			SeverityText: l.Level,
+			Body:         otlpAnyValue{StringValue: l.Line},
+			Attributes:   attrs,
+		})
+	}
+	req := otlpRequest{ResourceLogs: []otlpResourceLogs{{ScopeLogs: []otlpScopeLogs{{LogRecords: records}}}}}
+	req.ResourceLogs[0].Resource.Attributes = []otlpKeyValue{{Key: "host.name", Value: otlpAnyValue{StringValue: s.hostname}}}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		fmt.Println("error marshaling otlp payload", err)
+		return err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	defer func() {
+		batchSendDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		fmt.Println("error posting to otlp collector", err)
+		batchesSentTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		err := fmt.Errorf("otlp logs request failed: %s", string(b))
+		fmt.Println(err)
+		batchesSentTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	ioutil.ReadAll(resp.Body)
+	batchesSentTotal.WithLabelValues("success").Inc()
+	return nil
+}
+
+func (s *otlpSink) Close() error {
+	s.waitInflight(time.Now().Add(defaultShutdownDeadline))
+	return s.Flush()
+}
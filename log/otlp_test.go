@@ -0,0 +1,74 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestOTLPSink(url string) *otlpSink {
+	return &otlpSink{
+		url:      url,
+		hostname: "test-host",
+		headers:  map[string]string{"X-Test": "1"},
+		payload: &payload{
+			Lines: make([]line, 0),
+			mu:    &sync.RWMutex{},
+			name:  "otlp",
+		},
+	}
+}
+
+// TestOTLPSinkWriteLineFlushesOnBatchFull guards against the bug where
+// WriteLine's ready-to-flush signal was discarded, leaving otlpSink batches
+// sitting in memory until Close.
+func TestOTLPSinkWriteLineFlushesOnBatchFull(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestOTLPSink(srv.URL)
+	for i := 0; i < maxNumLines; i++ {
+		s.WriteLine(line{Line: "hello", Timestamp: int64(i)})
+	}
+	s.waitInflight(time.Now().Add(time.Second))
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected filling a batch to self-trigger one flush, got %d requests", got)
+	}
+}
+
+func TestOTLPSinkFlushSendsExpectedPayload(t *testing.T) {
+	var got otlpRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got2 := r.Header.Get("X-Test"); got2 != "1" {
+			t.Errorf("custom header missing, got %q", got2)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := newTestOTLPSink(srv.URL)
+	s.payload.Write(line{Line: "hello", Level: "error"})
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	records := got.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 1 {
+		t.Fatalf("got %d log records, want 1", len(records))
+	}
+	if records[0].Body.StringValue != "hello" || records[0].SeverityText != "error" {
+		t.Fatalf("unexpected record: %+v", records[0])
+	}
+}
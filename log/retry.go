@@ -0,0 +1,53 @@
+package log
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryPolicy controls how client.post retries a failed ingest POST with
+// exponential backoff and jitter.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+func defaultRetryPolicy(maxRetries int) retryPolicy {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return retryPolicy{maxRetries: maxRetries, baseDelay: 500 * time.Millisecond, maxDelay: 30 * time.Second}
+}
+
+// shouldRetry reports whether resp/err warrants another attempt: network
+// errors, 429s, and 5xx responses are considered transient.
+func (r retryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if resp == nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// delay computes how long to wait before the given 0-indexed retry attempt,
+// honoring a Retry-After header when the server sent one.
+func (r retryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	d := r.baseDelay * time.Duration(int64(1)<<uint(attempt))
+	if d <= 0 || d > r.maxDelay {
+		d = r.maxDelay
+	}
+	// full jitter, as recommended by the AWS architecture blog post on backoff
+	return time.Duration(rand.Int63n(int64(d)))
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sampler implements a first-N-per-second policy per level, so a burst of
+// identical log lines can't overwhelm a downstream sink.
+type sampler struct {
+	first int // 0 disables sampling
+
+	mu      sync.Mutex
+	windows map[Level]*sampleWindow
+}
+
+type sampleWindow struct {
+	second int64
+	count  int
+}
+
+func newSampler(first int) *sampler {
+	return &sampler{first: first, windows: make(map[Level]*sampleWindow)}
+}
+
+// allow reports whether a log at lvl should be emitted this second.
+func (s *sampler) allow(lvl Level) bool {
+	if s.first <= 0 {
+		return true
+	}
+	now := time.Now().Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.windows[lvl]
+	if w == nil || w.second != now {
+		w = &sampleWindow{second: now}
+		s.windows[lvl] = w
+	}
+	w.count++
+	return w.count <= s.first
+}
+
+var (
+	globalSamplerOnce sync.Once
+	globalSamplerVal  *sampler
+)
+
+// globalSampler is the sampler driven by PP_LOG_SAMPLE_FIRST. A value of 0
+// or an unset/invalid env var disables sampling.
+func globalSampler() *sampler {
+	globalSamplerOnce.Do(func() {
+		first, _ := strconv.Atoi(os.Getenv("PP_LOG_SAMPLE_FIRST"))
+		globalSamplerVal = newSampler(first)
+	})
+	return globalSamplerVal
+}
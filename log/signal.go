@@ -0,0 +1,93 @@
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownDeadline bounds how long InstallSignalHandler waits for the
+// final flush before giving up and letting the process die anyway.
+const defaultShutdownDeadline = 5 * time.Second
+
+var installSignalHandlerOnce sync.Once
+
+// InstallSignalHandler traps sig (SIGINT, SIGTERM and SIGHUP if none given)
+// and, on receipt, flushes the default sink with a 5s deadline, waiting for
+// any sends already in flight, then re-raises the signal so normal process
+// termination continues. The returned func cancels the handler without
+// waiting for a signal. defaultSink installs this automatically the first
+// time it resolves a sink, whether that's the LogDNA fallback or a
+// PP_LOG_SINK fan-out.
+func InstallSignalHandler(sig ...os.Signal) func() {
+	return InstallSignalHandlerWithDeadline(defaultShutdownDeadline, sig...)
+}
+
+// InstallSignalHandlerWithDeadline is InstallSignalHandler with a
+// caller-supplied flush deadline.
+func InstallSignalHandlerWithDeadline(deadline time.Duration, sig ...os.Signal) func() {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+	cancelled := make(chan struct{})
+
+	go func() {
+		select {
+		case s := <-ch:
+			signal.Stop(ch)
+			flushDefaultSink(deadline)
+			reraise(s)
+		case <-cancelled:
+			signal.Stop(ch)
+		}
+	}()
+
+	return func() {
+		close(cancelled)
+	}
+}
+
+// flushDefaultSink waits for any in-flight async flushes on the default sink
+// (resolving it via defaultSink if no logger has done so yet) to settle, then
+// forces a final flush, giving up on both once deadline has elapsed. Works
+// for whichever Sink defaultSink resolved — the LogDNA fallback client or a
+// PP_LOG_SINK fan-out — not just the LogDNA case.
+func flushDefaultSink(deadline time.Duration) {
+	sink := defaultSink()
+	if sink == nil {
+		return
+	}
+	end := time.Now().Add(deadline)
+	if w, ok := sink.(inflightWaiter); ok {
+		w.waitInflight(end)
+	}
+	done := make(chan struct{})
+	go func() {
+		sink.Flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(end)):
+	}
+}
+
+// reraise removes our handler and re-sends s to this process so whatever
+// the caller's own handler (or the Go runtime default) would have done
+// still happens.
+func reraise(s os.Signal) {
+	sig, ok := s.(syscall.Signal)
+	if !ok {
+		return
+	}
+	signal.Reset(s)
+	proc, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return
+	}
+	proc.Signal(sig)
+}
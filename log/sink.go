@@ -0,0 +1,140 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink is a pluggable destination for log lines. Implementations own their
+// own batching and transport; WriteLine reports whether the sink would like
+// to be flushed now (e.g. because it has filled a batch).
+type Sink interface {
+	WriteLine(l line) bool
+	Flush() error
+	Close() error
+}
+
+var (
+	sinkRegistryMu sync.Mutex
+	sinkRegistry   = map[string]func() Sink{}
+)
+
+// RegisterSink makes a sink factory available under name so it can be
+// selected via PP_LOG_SINK. Application code can call this from an init()
+// to plug in custom sinks alongside the built-in ones.
+func RegisterSink(name string, factory func() Sink) {
+	sinkRegistryMu.Lock()
+	defer sinkRegistryMu.Unlock()
+	sinkRegistry[name] = factory
+}
+
+func newSink(name string) Sink {
+	sinkRegistryMu.Lock()
+	factory, ok := sinkRegistry[name]
+	sinkRegistryMu.Unlock()
+	if !ok {
+		fmt.Println("unknown log sink", name)
+		return nil
+	}
+	return factory()
+}
+
+// sinksFromEnv builds the sink (or fan-out of sinks) requested by
+// PP_LOG_SINK, a comma separated list of registered sink names, e.g.
+// "loki,otlp". Returns nil if PP_LOG_SINK is unset or empty.
+func sinksFromEnv() Sink {
+	names := os.Getenv("PP_LOG_SINK")
+	if names == "" {
+		return nil
+	}
+	var sinks []Sink
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if s := newSink(name); s != nil {
+			sinks = append(sinks, s)
+		}
+	}
+	switch len(sinks) {
+	case 0:
+		return nil
+	case 1:
+		return sinks[0]
+	default:
+		return &fanoutSink{sinks: sinks}
+	}
+}
+
+// fanoutSink multiplexes a single logical sink across several underlying
+// sinks so a caller can ship the same lines to, say, Loki and OTLP at once.
+type fanoutSink struct {
+	sinks []Sink
+}
+
+func (f *fanoutSink) WriteLine(l line) bool {
+	ready := false
+	for _, s := range f.sinks {
+		if s.WriteLine(l) {
+			ready = true
+		}
+	}
+	return ready
+}
+
+func (f *fanoutSink) Flush() error {
+	var firstErr error
+	for _, s := range f.sinks {
+		if err := s.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// waitInflight waits for every underlying sink that tracks in-flight async
+// flushes to settle, so fanoutSink itself satisfies inflightWaiter.
+func (f *fanoutSink) waitInflight(deadline time.Time) {
+	for _, s := range f.sinks {
+		if w, ok := s.(inflightWaiter); ok {
+			w.waitInflight(deadline)
+		}
+	}
+}
+
+func (f *fanoutSink) Close() error {
+	var firstErr error
+	for _, s := range f.sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// inflightWaiter is implemented by sinks that spawn an async flush from
+// WriteLine and can wait for it to finish before a final flush, instead of
+// racing it. Optional: sinks that flush synchronously (or don't batch at
+// all, like syslog) have nothing to wait for.
+type inflightWaiter interface {
+	waitInflight(deadline time.Time)
+}
+
+// blockUntilDone waits for wg to drain or deadline to pass, whichever comes
+// first. Shared by every batch sink that tracks in-flight async flushes
+// spawned from WriteLine this way.
+func blockUntilDone(wg *sync.WaitGroup, deadline time.Time) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+	}
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewSinkUnknownNameReturnsNil(t *testing.T) {
+	if s := newSink("does-not-exist"); s != nil {
+		t.Fatalf("expected unknown sink name to return nil, got %T", s)
+	}
+}
+
+func TestRegisterSinkMakesItSelectable(t *testing.T) {
+	RegisterSink("test-sink", func() Sink { return &fakeSink{} })
+	s := newSink("test-sink")
+	if _, ok := s.(*fakeSink); !ok {
+		t.Fatalf("expected a *fakeSink, got %T", s)
+	}
+}
+
+type fakeSink struct {
+	mu      sync.Mutex
+	written []line
+	flushes int
+	closed  bool
+}
+
+func (f *fakeSink) WriteLine(l line) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.written = append(f.written, l)
+	return false
+}
+
+func (f *fakeSink) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flushes++
+	return nil
+}
+
+func (f *fakeSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func TestFanoutSinkWritesFlushesAndClosesEverySink(t *testing.T) {
+	a, b := &fakeSink{}, &fakeSink{}
+	f := &fanoutSink{sinks: []Sink{a, b}}
+
+	f.WriteLine(line{Line: "hello"})
+	if err := f.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for name, s := range map[string]*fakeSink{"a": a, "b": b} {
+		if len(s.written) != 1 {
+			t.Errorf("sink %s: got %d written lines, want 1", name, len(s.written))
+		}
+		if s.flushes != 1 {
+			t.Errorf("sink %s: got %d flushes, want 1", name, s.flushes)
+		}
+		if !s.closed {
+			t.Errorf("sink %s: expected Close to be called", name)
+		}
+	}
+}
+
+// TestFanoutSinkDeliversBatchToEveryRealSinkWithoutExplicitFlush is the
+// regression test for the bug where loki/elasticsearch/otlp sinks never
+// self-triggered a flush when a batch filled up: writing enough lines
+// through a fan-out of two real (HTTP-backed) sinks must ship the batch to
+// both backends without the caller ever calling Flush or Close.
+func TestFanoutSinkDeliversBatchToEveryRealSinkWithoutExplicitFlush(t *testing.T) {
+	var lokiRequests, otlpRequests int32
+	lokiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lokiRequests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer lokiSrv.Close()
+	otlpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&otlpRequests, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer otlpSrv.Close()
+
+	f := &fanoutSink{sinks: []Sink{
+		newTestLokiSink(lokiSrv.URL),
+		newTestOTLPSink(otlpSrv.URL),
+	}}
+
+	for i := 0; i < maxNumLines; i++ {
+		f.WriteLine(line{Line: "hello", Timestamp: int64(i)})
+	}
+	f.waitInflight(time.Now().Add(time.Second))
+
+	if got := atomic.LoadInt32(&lokiRequests); got != 1 {
+		t.Errorf("loki: got %d requests, want 1", got)
+	}
+	if got := atomic.LoadInt32(&otlpRequests); got != 1 {
+		t.Errorf("otlp: got %d requests, want 1", got)
+	}
+}
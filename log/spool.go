@@ -0,0 +1,170 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const spoolFileName = "spool.ndjson"
+
+// spool persists unsent log lines to disk so they survive process restarts,
+// similar in spirit to lumberjack's rotated append-only files. Lines are
+// appended as they fail to send and replayed by Drain.
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &spool{dir: dir, maxBytes: maxBytes}
+	if err := s.openCurrentLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *spool) openCurrentLocked() error {
+	f, err := os.OpenFile(filepath.Join(s.dir, spoolFileName), os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		s.size = info.Size()
+	}
+	s.file = f
+	spoolBytes.Set(float64(s.size))
+	return nil
+}
+
+// rotateLocked renames the current spool file aside so a fresh one can be
+// started; the renamed file is picked up by Drain like any other.
+func (s *spool) rotateLocked() {
+	s.file.Close()
+	rotated := filepath.Join(s.dir, fmt.Sprintf("spool-%d.ndjson", time.Now().UnixNano()))
+	os.Rename(filepath.Join(s.dir, spoolFileName), rotated)
+	s.openCurrentLocked()
+	s.size = 0
+}
+
+// Write appends lines to the spool as NDJSON, rotating once maxBytes (if
+// configured) would be exceeded.
+func (s *spool) Write(lines []line) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, l := range lines {
+		b, err := json.Marshal(l)
+		if err != nil {
+			continue
+		}
+		b = append(b, '\n')
+		if s.maxBytes > 0 && s.size+int64(len(b)) > s.maxBytes {
+			s.rotateLocked()
+		}
+		n, err := s.file.Write(b)
+		if err != nil {
+			return err
+		}
+		s.size += int64(n)
+	}
+	spoolBytes.Set(float64(s.size))
+	return nil
+}
+
+// Drain replays every spooled line through send, batched at maxNumLines,
+// stopping once deadline passes. Lines that are successfully sent are
+// removed from disk; anything left (send failure or deadline) stays spooled
+// for the next Drain call.
+func (s *spool) Drain(deadline time.Time, send func([]line) bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file.Close()
+
+	files, _ := filepath.Glob(filepath.Join(s.dir, "spool*.ndjson"))
+	sort.Strings(files)
+	for _, f := range files {
+		if time.Now().After(deadline) {
+			break
+		}
+		remaining := s.drainFile(f, deadline, send)
+		if len(remaining) == 0 {
+			os.Remove(f)
+		} else {
+			s.rewriteFile(f, remaining)
+		}
+	}
+	s.openCurrentLocked()
+}
+
+func (s *spool) drainFile(path string, deadline time.Time, send func([]line) bool) []line {
+	lines := readSpoolFile(path)
+	var batch []line
+	for len(lines) > 0 {
+		if time.Now().After(deadline) {
+			return lines
+		}
+		n := maxNumLines
+		if n > len(lines) {
+			n = len(lines)
+		}
+		batch, lines = lines[:n], lines[n:]
+		if !send(batch) {
+			return append(batch, lines...)
+		}
+	}
+	return nil
+}
+
+func (s *spool) rewriteFile(path string, remaining []line) {
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Println("error rewriting logdna spool file", err)
+		return
+	}
+	defer f.Close()
+	for _, l := range remaining {
+		b, err := json.Marshal(l)
+		if err != nil {
+			continue
+		}
+		f.Write(b)
+		f.Write([]byte{'\n'})
+	}
+}
+
+func readSpoolFile(path string) []line {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var lines []line
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var l line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err == nil {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
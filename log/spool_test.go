@@ -0,0 +1,82 @@
+package log
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpoolWriteAndDrain(t *testing.T) {
+	s, err := newSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("newSpool: %v", err)
+	}
+
+	lines := sampleLines(5)
+	if err := s.Write(lines); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	var got []line
+	s.Drain(time.Now().Add(time.Second), func(ls []line) bool {
+		got = append(got, ls...)
+		return true
+	})
+	if len(got) != len(lines) {
+		t.Fatalf("got %d lines, want %d", len(got), len(lines))
+	}
+}
+
+func TestSpoolDrainKeepsUnsentOnFailure(t *testing.T) {
+	s, err := newSpool(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("newSpool: %v", err)
+	}
+
+	lines := sampleLines(3)
+	if err := s.Write(lines); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	s.Drain(time.Now().Add(time.Second), func(ls []line) bool { return false })
+
+	var got []line
+	s.Drain(time.Now().Add(time.Second), func(ls []line) bool {
+		got = append(got, ls...)
+		return true
+	})
+	if len(got) != len(lines) {
+		t.Fatalf("expected lines to survive a failed drain, got %d want %d", len(got), len(lines))
+	}
+}
+
+func TestSpoolRotatesOnMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, 64)
+	if err != nil {
+		t.Fatalf("newSpool: %v", err)
+	}
+
+	for _, l := range sampleLines(20) {
+		if err := s.Write([]line{l}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "spool*.ndjson"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(files) < 2 {
+		t.Fatalf("expected rotation to produce multiple spool files, got %d", len(files))
+	}
+
+	var got []line
+	s.Drain(time.Now().Add(time.Second), func(ls []line) bool {
+		got = append(got, ls...)
+		return true
+	})
+	if len(got) != 20 {
+		t.Fatalf("expected all 20 lines to survive rotation, got %d", len(got))
+	}
+}
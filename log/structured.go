@@ -0,0 +1,55 @@
+package log
+
+// shouldLog reports whether a log at lvl passes the global level filter and
+// sampling policy. Used by StructuredLogger only: the Log(keyvals...) shim
+// applies just the level filter (levelAllowed) so a message logged through
+// StructuredLogger and forwarded down to the shim isn't sampled twice.
+func shouldLog(lvl Level) bool {
+	if lvl < minLevel() {
+		return false
+	}
+	return globalSampler().allow(lvl)
+}
+
+// StructuredLogger provides zap-style leveled logging with typed Fields on
+// top of the existing keyval-based Logger pipeline.
+type StructuredLogger struct {
+	next Logger
+}
+
+// NewStructured wraps next with leveled, field-aware logging methods.
+func NewStructured(next Logger) *StructuredLogger {
+	return &StructuredLogger{next: next}
+}
+
+func (l *StructuredLogger) log(lvl Level, msg string, fields []Field) {
+	if !shouldLog(lvl) || l.next == nil {
+		return
+	}
+	keyvals := make([]interface{}, 0, 4+len(fields)*2)
+	keyvals = append(keyvals, "msg", msg, "level", lvl.String())
+	for _, f := range fields {
+		keyvals = append(keyvals, f.Key, f.value())
+	}
+	l.next.Log(keyvals...)
+}
+
+// Debug logs msg at DebugLevel with the given fields.
+func (l *StructuredLogger) Debug(msg string, fields ...Field) {
+	l.log(DebugLevel, msg, fields)
+}
+
+// Info logs msg at InfoLevel with the given fields.
+func (l *StructuredLogger) Info(msg string, fields ...Field) {
+	l.log(InfoLevel, msg, fields)
+}
+
+// Warn logs msg at WarnLevel with the given fields.
+func (l *StructuredLogger) Warn(msg string, fields ...Field) {
+	l.log(WarnLevel, msg, fields)
+}
+
+// Error logs msg at ErrorLevel with the given fields.
+func (l *StructuredLogger) Error(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fields)
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSyslogFacility = 16 // local0
+
+// syslogSink ships log lines as RFC5424 syslog messages over UDP or TCP.
+// Unlike the batch-oriented sinks, each line is written as soon as it
+// arrives since syslog has no concept of a bulk request.
+type syslogSink struct {
+	network  string
+	addr     string
+	hostname string
+	appname  string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogSink() Sink {
+	addr := os.Getenv("PP_LOG_SYSLOG_ADDR")
+	if addr == "" {
+		return nil
+	}
+	network := os.Getenv("PP_LOG_SYSLOG_NETWORK")
+	if network == "" {
+		network = "udp"
+	}
+	hostname := os.Getenv("PP_HOSTNAME")
+	if hostname == "" {
+		hostname = "hostname.not.provided"
+	}
+	appname := os.Getenv("PP_LOG_SYSLOG_APP")
+	if appname == "" {
+		appname = "go-common"
+	}
+	return &syslogSink{
+		network:  network,
+		addr:     addr,
+		hostname: hostname,
+		appname:  appname,
+	}
+}
+
+func init() {
+	RegisterSink("syslog", newSyslogSink)
+}
+
+var syslogSeverities = map[string]int{
+	"debug": 7,
+	"info":  6,
+	"warn":  4,
+	"error": 3,
+}
+
+func severity(level string) int {
+	if sev, ok := syslogSeverities[strings.ToLower(level)]; ok {
+		return sev
+	}
+	return 6
+}
+
+func (s *syslogSink) dial() (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	conn, err := net.Dial(s.network, s.addr)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// WriteLine formats l as an RFC5424 message and writes it immediately.
+func (s *syslogSink) WriteLine(l line) bool {
+	conn, err := s.dial()
+	if err != nil {
+		fmt.Println("error dialing syslog", err)
+		return false
+	}
+	pri := defaultSyslogFacility*8 + severity(l.Level)
+	msg := fmt.Sprintf("<%d>1 %s %s %s - - - %s\n",
+		pri,
+		time.Unix(0, l.Timestamp*int64(time.Millisecond)).UTC().Format(time.RFC3339),
+		s.hostname,
+		s.appname,
+		l.Line,
+	)
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		fmt.Println("error writing to syslog", err)
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}
+	return false
+}
+
+// Flush is a no-op since syslog messages are written as they arrive.
+func (s *syslogSink) Flush() error {
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		err := s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
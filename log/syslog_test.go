@@ -0,0 +1,58 @@
+package log
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSinkWriteLineSendsRFC5424Message(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer pc.Close()
+
+	s := &syslogSink{
+		network:  "udp",
+		addr:     pc.LocalAddr().String(),
+		hostname: "test-host",
+		appname:  "go-common-test",
+	}
+
+	if ready := s.WriteLine(line{Line: "hello", Level: "error", Timestamp: 0}); ready {
+		t.Fatal("syslogSink.WriteLine should never report ready, it writes immediately")
+	}
+
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	got := string(buf[:n])
+
+	wantPri := "<131>" // local0 facility (16*8=128) + error severity (3)
+	if !strings.HasPrefix(got, wantPri) {
+		t.Fatalf("unexpected priority in message %q, want prefix %q", got, wantPri)
+	}
+	if !strings.Contains(got, "test-host") || !strings.Contains(got, "go-common-test") || !strings.Contains(got, "hello") {
+		t.Fatalf("message missing expected fields: %q", got)
+	}
+}
+
+func TestSeverityMapsKnownLevels(t *testing.T) {
+	cases := map[string]int{
+		"debug": 7,
+		"info":  6,
+		"warn":  4,
+		"error": 3,
+		"bogus": 6,
+	}
+	for lvl, want := range cases {
+		if got := severity(lvl); got != want {
+			t.Errorf("severity(%q) = %d, want %d", lvl, got, want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package log
+
+import "strconv"
+
+// sampleLines builds n lines with enough repeated structure to be
+// representative of a real batch, for use by both correctness tests and
+// the compression benchmarks.
+func sampleLines(n int) []line {
+	lines := make([]line, n)
+	for i := range lines {
+		lines[i] = line{
+			Timestamp: int64(i),
+			Line:      "request completed id=" + strconv.Itoa(i) + " path=/v1/widgets status=200",
+			App:       "go-common-test",
+			Level:     "info",
+			Meta: map[string]interface{}{
+				"request_id": i,
+				"path":       "/v1/widgets",
+			},
+		}
+	}
+	return lines
+}